@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// ScanEngine performs a single port scan using whatever technique it
+// implements (full TCP connect, half-open SYN, or UDP) and reports the
+// result. Selected via --scan-type. ip is what gets dialed; host is the
+// original hostname (equal to ip when the target was already a literal
+// IP or came from an --input file), passed through separately so
+// protocol probes that need the name - HTTP Host header, TLS SNI - see
+// it even though the connection itself goes straight to the resolved IP.
+type ScanEngine interface {
+	Scan(ctx context.Context, ip, host string, port int) ScanResult
+}
+
+// newScanEngine builds the engine named by config.ScanType. validateConfig
+// has already rejected unknown scan types and missing privileges, so the
+// only error path here is the SYN engine failing to open its raw socket.
+func newScanEngine(config *Config) (ScanEngine, error) {
+	timeout := time.Duration(config.Timeout) * time.Second
+
+	switch config.ScanType {
+	case "syn":
+		return newSYNEngine(timeout)
+	case "udp":
+		return &udpEngine{timeout: timeout}, nil
+	default:
+		return &connectEngine{
+			dialer:  net.Dialer{Timeout: timeout},
+			probes:  config.Probes,
+			timeout: timeout,
+		}, nil
+	}
+}
+
+// hasRawSocketPrivilege reports whether the process can plausibly open a
+// raw socket for SYN scanning. It's a best-effort euid check, not a
+// capability check - newSYNEngine still surfaces a clear error if opening
+// the socket fails for any reason (missing CAP_NET_RAW, seccomp, etc).
+func hasRawSocketPrivilege() bool {
+	return os.Geteuid() == 0
+}
+
+// connectEngine is the original full TCP-connect scan: dial the port,
+// and if it succeeds, dispatch to the protocol-aware probe registry for
+// a banner and service metadata.
+type connectEngine struct {
+	dialer  net.Dialer
+	probes  []string
+	timeout time.Duration
+}
+
+func (e *connectEngine) Scan(ctx context.Context, ip, host string, port int) ScanResult {
+	addr := net.JoinHostPort(ip, strconv.Itoa(port))
+
+	conn, err := e.dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return ScanResult{Target: ip, Port: port, Open: false}
+	}
+	defer conn.Close()
+
+	probe := selectProbe(port, e.probes)
+	banner, meta, _ := probe.Probe(conn, host, e.timeout)
+
+	return ScanResult{
+		Target:      ip,
+		Port:        port,
+		Open:        true,
+		Banner:      banner,
+		ServiceMeta: meta,
+	}
+}
+
+// udpPayloads holds a protocol-specific datagram to elicit a response
+// from common UDP services; ports without an entry fall back to an
+// empty datagram.
+var udpPayloads = map[int][]byte{
+	53: { // minimal DNS query for "." A record
+		0x00, 0x00, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x01,
+	},
+	123: append([]byte{0x1b}, make([]byte, 47)...), // NTP client request
+	161: { // SNMP v1 GetRequest for sysDescr.0, community "public"
+		0x30, 0x26, 0x02, 0x01, 0x00, 0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c',
+		0xa0, 0x19, 0x02, 0x01, 0x01, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00,
+		0x30, 0x0e, 0x30, 0x0c, 0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00, 0x05, 0x00,
+	},
+}
+
+// udpEngine sends a protocol-specific probe datagram and classifies the
+// port based on the response: a reply means open, an ICMP port
+// unreachable (surfaced by the kernel as a write/read error on a
+// connected UDP socket) means closed, and silence means open|filtered -
+// reported via Banner but not counted as Open, since we can't tell a
+// dropped packet from a filtered one.
+type udpEngine struct {
+	timeout time.Duration
+}
+
+func (e *udpEngine) Scan(ctx context.Context, ip, host string, port int) ScanResult {
+	addr := net.JoinHostPort(ip, strconv.Itoa(port))
+
+	conn, err := (&net.Dialer{Timeout: e.timeout}).DialContext(ctx, "udp", addr)
+	if err != nil {
+		return ScanResult{Target: ip, Port: port, Open: false, Banner: "closed"}
+	}
+	defer conn.Close()
+
+	payload, ok := udpPayloads[port]
+	if !ok {
+		payload = []byte{}
+	}
+
+	conn.SetDeadline(time.Now().Add(e.timeout))
+	if _, err := conn.Write(payload); err != nil {
+		return ScanResult{Target: ip, Port: port, Open: false, Banner: "closed"}
+	}
+
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	if err != nil {
+		if isICMPUnreachable(err) {
+			return ScanResult{Target: ip, Port: port, Open: false, Banner: "closed"}
+		}
+		// No reply and no unreachable error: can't distinguish open from
+		// filtered without an ICMP listener, so report the ambiguous
+		// state via the banner but don't count it as a confirmed open
+		// port - that would vastly inflate OpenPorts on a UDP range scan.
+		return ScanResult{Target: ip, Port: port, Open: false, Banner: "open|filtered"}
+	}
+
+	return ScanResult{
+		Target: ip,
+		Port:   port,
+		Open:   true,
+		Banner: "open",
+		ServiceMeta: map[string]string{
+			"response_bytes": strconv.Itoa(n),
+		},
+	}
+}
+
+// isICMPUnreachable reports whether err is the kernel reporting an ICMP
+// port-unreachable back to a connected UDP socket. On Linux/BSD this
+// surfaces as a "connection refused" syscall error.
+func isICMPUnreachable(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
@@ -1,14 +1,19 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"net"
+	"io"
+	"math/rand"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -20,14 +25,40 @@ type Config struct {
 	Timeout    int
 	Specific   []int
 	JSONOutput bool
+	RateCount  int
+	RateWindow time.Duration
+	Deadline   time.Duration
+	Probes     []string
+	JSONLMode  bool
+	OutputFile string
+	InputFile  string
+	ScanType   string
 }
 
-// ScanResult: information about a single port scan
-type ScanResult struct {
+// inputTarget is one line of a --input JSONL file.
+type inputTarget struct {
 	Target string `json:"target"`
 	Port   int    `json:"port"`
-	Open   bool   `json:"open"`
-	Banner string `json:"banner,omitempty"`
+}
+
+// scanTask is one target to probe. IP is what actually gets dialed
+// (resolved once up front by resolveTargets); Host is the original
+// hostname, kept alongside it so protocol probes that need the name -
+// the HTTP Host header, the TLS SNI server name - still see it even
+// though the connection goes straight to the cached IP.
+type scanTask struct {
+	IP   string
+	Host string
+	Port int
+}
+
+// ScanResult: information about a single port scan
+type ScanResult struct {
+	Target      string            `json:"target"`
+	Port        int               `json:"port"`
+	Open        bool              `json:"open"`
+	Banner      string            `json:"banner,omitempty"`
+	ServiceMeta map[string]string `json:"service_meta,omitempty"`
 }
 
 // ScanSummary: Scan Summary...
@@ -38,20 +69,48 @@ type ScanSummary struct {
 	Targets     []string      `json:"targets"`
 	PortRange   string        `json:"port_range"`
 	WorkerCount int           `json:"worker_count"`
+	Partial     bool          `json:"partial,omitempty"`
 }
 
 func main() {
 	//parse command line flags
 	config := parseFlags()
 
+	// Expand CIDR blocks, IP ranges, and @file.txt includes in --targets
+	// into a flat target list before validating it.
+	if err := expandConfigTargets(config); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Validate the configuration
 	if err := validateConfig(config); err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 
+	// Ctrl-C (or a terminating signal) cancels the context so in-flight
+	// workers abort their dials and the collector flushes whatever
+	// results were gathered so far instead of losing them to kill -9.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if config.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Deadline)
+		defer cancel()
+	}
+
 	// Run the scanner
-	results, summary := runScanner(config)
+	results, summary := runScanner(ctx, config)
+
+	// In --jsonl mode results were already streamed out by the collector
+	// as they arrived; just report the summary so stdout stays a clean
+	// stream of result objects for piping through jq.
+	if config.JSONLMode {
+		fmt.Fprintf(os.Stderr, "\n=== Scan Summary ===\n%+v\n", summary)
+		return
+	}
 
 	// Print results based on output format
 	if config.JSONOutput {
@@ -73,6 +132,14 @@ func parseFlags() *Config {
 	timeout := flag.Int("timeout", 5, "Connection timeout in seconds")
 	ports := flag.String("ports", "", "Comma-separated list of specific ports to scan")
 	jsonOutput := flag.Bool("json", false, "Output results in JSON format")
+	rate := flag.Int("rate", 0, "Maximum connection attempts per rate-window (0 = unlimited)")
+	rateWindow := flag.Duration("rate-window", time.Second, "Window over which --rate is enforced")
+	deadline := flag.Duration("deadline", 0, "Overall scan deadline, e.g. 30s (0 = no deadline)")
+	probes := flag.String("probes", "auto", "Comma-separated probes to use (http,tls,ssh,smtp,ftp,pop3,auto)")
+	jsonlMode := flag.Bool("jsonl", false, "Stream each result as a JSONL line instead of buffering in memory")
+	output := flag.String("output", "", "File to write results to (default stdout); used with --jsonl")
+	input := flag.String("input", "", "JSONL file of {\"target\":...,\"port\":...} objects to scan instead of a port range")
+	scanType := flag.String("scan-type", "connect", "Scan engine to use: connect, syn, or udp")
 
 	flag.Parse()
 
@@ -98,11 +165,26 @@ func parseFlags() *Config {
 	config.Workers = *workers
 	config.Timeout = *timeout
 	config.JSONOutput = *jsonOutput
+	config.RateCount = *rate
+	config.RateWindow = *rateWindow
+	config.Deadline = *deadline
+	config.Probes = strings.Split(*probes, ",")
+	config.JSONLMode = *jsonlMode
+	config.OutputFile = *output
+	config.InputFile = *input
+	config.ScanType = *scanType
 
 	return config
 }
 
 func validateConfig(config *Config) error {
+	if config.InputFile != "" {
+		if _, err := os.Stat(config.InputFile); err != nil {
+			return fmt.Errorf("input file: %w", err)
+		}
+		return validateWorkerConfig(config)
+	}
+
 	if len(config.Targets) == 0 {
 		return fmt.Errorf("no targets specified")
 	}
@@ -125,6 +207,12 @@ func validateConfig(config *Config) error {
 		}
 	}
 
+	return validateWorkerConfig(config)
+}
+
+// validateWorkerConfig checks the settings that apply regardless of how
+// the task list was built (range, specific ports, or --input file).
+func validateWorkerConfig(config *Config) error {
 	if config.Workers < 1 {
 		return fmt.Errorf("worker count must be at least 1")
 	}
@@ -133,57 +221,236 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("timeout must be at least 1 second")
 	}
 
+	if config.RateCount < 0 {
+		return fmt.Errorf("rate must not be negative")
+	}
+
+	if config.RateCount > 0 && config.RateWindow <= 0 {
+		return fmt.Errorf("rate-window must be positive when rate is set")
+	}
+
+	if config.Deadline < 0 {
+		return fmt.Errorf("deadline must not be negative")
+	}
+
+	switch config.ScanType {
+	case "connect", "udp":
+		// no special privileges required
+	case "syn":
+		if !hasRawSocketPrivilege() {
+			return fmt.Errorf("--scan-type=syn requires root (or CAP_NET_RAW) to craft raw packets")
+		}
+	default:
+		return fmt.Errorf("unknown --scan-type %q (want connect, syn, or udp)", config.ScanType)
+	}
+
 	return nil
 }
 
-func runScanner(config *Config) ([]ScanResult, ScanSummary) {
-	var wg sync.WaitGroup
-	tasks := make(chan string, config.Workers*2)
-	results := make(chan ScanResult, config.Workers*2)
+// RateLimiter is a continuous token bucket shared by all workers so the
+// combined connect rate across the whole worker pool stays within budget.
+// Tokens refill smoothly at burst/window per second rather than in one
+// lump at a window boundary, so the outgoing connect rate stays level
+// instead of bursting to full speed and then stalling - the fixed-window
+// version this replaced let every worker race through a full burst the
+// instant the window ticked over, producing exactly the traffic spikes
+// --rate is meant to avoid.
+type RateLimiter struct {
+	mu         sync.Mutex
+	tokens     float64
+	burst      float64
+	ratePerSec float64
+	lastFill   time.Time
+}
+
+// NewRateLimiter creates a token bucket allowing up to burst take
+// operations per window, refilled continuously at burst/window tokens
+// per second. A nil *RateLimiter is valid and Take becomes a no-op, so
+// callers can construct one unconditionally and skip the "is rate
+// limiting enabled" check at every call site.
+func NewRateLimiter(burst int, window time.Duration) *RateLimiter {
+	if burst <= 0 {
+		return nil
+	}
+	return &RateLimiter{
+		tokens:     float64(burst),
+		burst:      float64(burst),
+		ratePerSec: float64(burst) / window.Seconds(),
+		lastFill:   time.Now(),
+	}
+}
+
+// Take blocks until a token is available, refilling the bucket in
+// proportion to elapsed time since the last refill.
+func (r *RateLimiter) Take() {
+	if r == nil {
+		return
+	}
+	for {
+		r.mu.Lock()
+		now := time.Now()
+		if elapsed := now.Sub(r.lastFill); elapsed > 0 {
+			r.tokens += elapsed.Seconds() * r.ratePerSec
+			if r.tokens > r.burst {
+				r.tokens = r.burst
+			}
+			r.lastFill = now
+		}
+		if r.tokens >= 1 {
+			r.tokens--
+			r.mu.Unlock()
+			return
+		}
+		wait := time.Duration((1 - r.tokens) / r.ratePerSec * float64(time.Second))
+		r.mu.Unlock()
+		if wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+}
+
+func runScanner(ctx context.Context, config *Config) ([]ScanResult, ScanSummary) {
 	allResults := []ScanResult{}
 
-	// Create dialer with timeout
-	dialer := net.Dialer{
-		Timeout: time.Duration(config.Timeout) * time.Second,
+	engine, err := newScanEngine(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "scan engine: %v\n", err)
+		return allResults, ScanSummary{}
 	}
 
+	var wg sync.WaitGroup
+	tasks := make(chan scanTask, config.Workers*2)
+	results := make(chan ScanResult, config.Workers*2)
+
+	// Shared token bucket so the combined connect rate across all workers
+	// respects --rate / --rate-window, regardless of worker count.
+	limiter := NewRateLimiter(config.RateCount, config.RateWindow)
+
 	// Start workers
 	for i := 0; i < config.Workers; i++ {
 		wg.Add(1)
-		go worker(&wg, tasks, results, dialer, config)
+		go worker(ctx, &wg, tasks, results, engine, limiter)
 	}
 
-	// Start result collector
+	// Start result collector. In --jsonl mode each result is streamed out
+	// as soon as it's produced instead of being buffered in allResults,
+	// so a multi-million-port scan doesn't have to fit in memory. Either
+	// way openPorts is tallied here as results arrive, since allResults
+	// stays empty in --jsonl mode.
+	openPorts := 0
 	var collectorWg sync.WaitGroup
 	collectorWg.Add(1)
 	go func() {
 		defer collectorWg.Done()
+
+		if !config.JSONLMode {
+			for res := range results {
+				if res.Open {
+					openPorts++
+				}
+				allResults = append(allResults, res)
+			}
+			return
+		}
+
+		out, closeOut, err := openOutput(config.OutputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "jsonl output: %v\n", err)
+			for range results {
+				// drain so workers don't block
+			}
+			return
+		}
+		defer closeOut()
+
+		enc := json.NewEncoder(out)
 		for res := range results {
-			allResults = append(allResults, res)
+			if res.Open {
+				openPorts++
+			}
+			if err := enc.Encode(res); err != nil {
+				fmt.Fprintf(os.Stderr, "jsonl output: %v\n", err)
+			}
 		}
 	}()
 
 	// Record start time
 	startTime := time.Now()
 
-	// Generate tasks
+	// Generate tasks, bailing out early if the context is cancelled so a
+	// Ctrl-C during task generation doesn't block forever on a full
+	// channel that workers have stopped draining. taskCount is the
+	// ground truth for TotalPorts in the summary below - it's what was
+	// actually emitted, whether that came from a range, specific ports,
+	// or an --input file's line count.
+	taskCount := 0
 	go func() {
+		defer close(tasks)
+		emit := func(t scanTask) bool {
+			select {
+			case tasks <- t:
+				taskCount++
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		if config.InputFile != "" {
+			// Resumed/sharded scans care about the exact order of a
+			// --input file, so it's read and emitted as-is.
+			if err := readInputTargets(config.InputFile, emit); err != nil {
+				fmt.Fprintf(os.Stderr, "input file: %v\n", err)
+			}
+			return
+		}
+
+		// Resolve each target once up front so every worker dials the
+		// same cached IP, then build and shuffle the full target x port
+		// work list so a large CIDR scan doesn't hammer one host
+		// sequentially while the rest sit idle. The original hostname
+		// rides along as Host so probes (HTTP Host header, TLS SNI)
+		// still see the name instead of the resolved IP.
+		resolved := resolveTargets(config.Targets)
+		type dedupKey struct {
+			ip   string
+			port int
+		}
+		seen := make(map[dedupKey]bool)
+		var work []scanTask
+		appendTask := func(target string, port int) {
+			ip := resolved[target]
+			key := dedupKey{ip: ip, port: port}
+			if seen[key] {
+				return
+			}
+			seen[key] = true
+			work = append(work, scanTask{IP: ip, Host: target, Port: port})
+		}
+
 		if len(config.Specific) > 0 {
-			// Scan specific ports
 			for _, target := range config.Targets {
 				for _, port := range config.Specific {
-					tasks <- net.JoinHostPort(target, strconv.Itoa(port))
+					appendTask(target, port)
 				}
 			}
 		} else {
-			// Scan port range
 			for _, target := range config.Targets {
 				for port := config.StartPort; port <= config.EndPort; port++ {
-					tasks <- net.JoinHostPort(target, strconv.Itoa(port))
+					appendTask(target, port)
 				}
 			}
 		}
-		close(tasks)
+
+		rand.New(rand.NewSource(time.Now().UnixNano())).Shuffle(len(work), func(i, j int) {
+			work[i], work[j] = work[j], work[i]
+		})
+
+		for _, t := range work {
+			if !emit(t) {
+				return
+			}
+		}
 	}()
 
 	// Wait for workers to finish
@@ -194,77 +461,90 @@ func runScanner(config *Config) ([]ScanResult, ScanSummary) {
 	// Calculate time taken
 	timeTaken := time.Since(startTime)
 
-	// Counting open ports
-	openPorts := 0
-	for _, res := range allResults {
-		if res.Open {
-			openPorts++
-		}
-	}
-
-	// summary
+	// summary. PortRange is purely descriptive; TotalPorts is always
+	// taskCount - what the producer actually emitted - so it's accurate
+	// for a port range, a specific-ports list, or an --input file, and
+	// already reflects dedup and any early cancellation.
 	var portRange string
-	if len(config.Specific) > 0 {
+	switch {
+	case config.InputFile != "":
+		portRange = fmt.Sprintf("input file: %s", config.InputFile)
+	case len(config.Specific) > 0:
 		portRange = fmt.Sprintf("specific ports: %v", config.Specific)
-	} else {
+	default:
 		portRange = fmt.Sprintf("%d-%d", config.StartPort, config.EndPort)
 	}
 
-	totalPorts := 0
-	if len(config.Specific) > 0 {
-		totalPorts = len(config.Specific) * len(config.Targets)
-	} else {
-		totalPorts = (config.EndPort - config.StartPort + 1) * len(config.Targets)
-	}
-
 	summary := ScanSummary{
-		TotalPorts:  totalPorts,
+		TotalPorts:  taskCount,
 		OpenPorts:   openPorts,
 		TimeTaken:   timeTaken,
 		Targets:     config.Targets,
 		PortRange:   portRange,
 		WorkerCount: config.Workers,
+		Partial:     ctx.Err() != nil,
 	}
 
 	return allResults, summary
 }
 
-func worker(wg *sync.WaitGroup, tasks chan string, results chan ScanResult, dialer net.Dialer, config *Config) {
-	defer wg.Done()
+// openOutput resolves --output to a writer: stdout if path is empty,
+// otherwise a freshly created file. The returned close func is always
+// safe to call, even for stdout.
+func openOutput(path string) (io.Writer, func(), error) {
+	if path == "" {
+		return os.Stdout, func() {}, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, func() { f.Close() }, nil
+}
 
-	for addr := range tasks {
-		host, portStr, _ := net.SplitHostPort(addr)
-		port, _ := strconv.Atoi(portStr)
-
-		// Try to connect
-		conn, err := dialer.Dial("tcp", addr)
-		if err == nil {
-			// Connection successful, port open
-			banner := ""
-
-			// read banner 
-			conn.SetReadDeadline(time.Now().Add(time.Duration(config.Timeout) * time.Second))
-			buf := make([]byte, 1024)
-			n, _ := conn.Read(buf)
-			if n > 0 {
-				banner = strings.TrimSpace(string(buf[:n]))
-			}
-			conn.Close()
+// readInputTargets reads a JSONL file of {"target":...,"port":...}
+// objects and emits one task per line. Lines that fail to parse are
+// skipped with a warning rather than aborting the whole scan.
+func readInputTargets(path string, emit func(t scanTask) bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
 
-			results <- ScanResult{
-				Target: host,
-				Port:   port,
-				Open:   true,
-				Banner: banner,
-			}
-		} else {
-			//conenction fails, port closed.
-			results <- ScanResult{
-				Target: host,
-				Port:   port,
-				Open:   false,
-			}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var t inputTarget
+		if err := json.Unmarshal([]byte(line), &t); err != nil {
+			fmt.Fprintf(os.Stderr, "input file: skipping invalid line: %v\n", err)
+			continue
+		}
+		// An --input file gives us a single string per line, so IP and
+		// Host are the same value here; resolveTargets isn't involved.
+		if !emit(scanTask{IP: t.Target, Host: t.Target, Port: t.Port}) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+func worker(ctx context.Context, wg *sync.WaitGroup, tasks chan scanTask, results chan ScanResult, engine ScanEngine, limiter *RateLimiter) {
+	defer wg.Done()
+
+	for t := range tasks {
+		if ctx.Err() != nil {
+			return
 		}
+
+		// Wait for a rate-limit token before issuing the connect so the
+		// pool-wide connect rate never exceeds --rate / --rate-window.
+		limiter.Take()
+
+		results <- engine.Scan(ctx, t.IP, t.Host, t.Port)
 	}
 }
 
@@ -277,12 +557,20 @@ func printHumanResults(results []ScanResult, summary ScanSummary) {
 			if res.Banner != "" {
 				fmt.Printf(" - Banner: %s", res.Banner)
 			}
+			for _, key := range []string{"server", "tls_version", "common_name", "sans", "cipher"} {
+				if value, ok := res.ServiceMeta[key]; ok {
+					fmt.Printf(" [%s: %s]", key, value)
+				}
+			}
 			fmt.Println()
 		}
 	}
 
 	// Print summary
 	fmt.Println("\n=== Scan Summary ===")
+	if summary.Partial {
+		fmt.Println("(partial results - scan was cancelled or hit its deadline)")
+	}
 	fmt.Printf("Targets: %v\n", summary.Targets)
 	fmt.Printf("Port range: %s\n", summary.PortRange)
 	fmt.Printf("Total ports scanned: %d\n", summary.TotalPorts)
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Probe is a protocol-aware fingerprinter dispatched once a TCP connect
+// succeeds. Match reports whether the probe applies to a given port;
+// Probe performs the protocol exchange and returns a human-readable
+// banner plus any structured metadata it was able to extract.
+type Probe interface {
+	Name() string
+	Match(port int) bool
+	Probe(conn net.Conn, host string, timeout time.Duration) (banner string, meta map[string]string, err error)
+}
+
+// probeRegistry lists the built-in probes in dispatch priority order.
+// selectProbe walks this list and uses the first enabled match.
+var probeRegistry = []Probe{
+	httpProbe{},
+	tlsProbe{},
+	sshProbe{},
+	smtpProbe{},
+	ftpProbe{},
+	pop3Probe{},
+}
+
+// selectProbe picks the probe to use for a port given the set of
+// enabled probe names from --probes. "auto" (the default) enables every
+// registered probe; otherwise only the named probes are considered. If
+// nothing matches, passiveProbe falls back to a blind read so unknown
+// ports still get whatever banner they offer up front.
+func selectProbe(port int, enabled []string) Probe {
+	auto := false
+	allowed := make(map[string]bool, len(enabled))
+	for _, name := range enabled {
+		name = strings.TrimSpace(name)
+		if name == "auto" {
+			auto = true
+		}
+		allowed[name] = true
+	}
+
+	for _, p := range probeRegistry {
+		if !p.Match(port) {
+			continue
+		}
+		if auto || allowed[p.Name()] {
+			return p
+		}
+	}
+	return passiveProbe{}
+}
+
+// passiveProbe is the fallback for ports with no dedicated probe (or
+// where that probe was disabled via --probes): it just reads whatever
+// the remote side sends first, matching the scanner's original
+// blind-read behavior.
+type passiveProbe struct{}
+
+func (passiveProbe) Name() string      { return "passive" }
+func (passiveProbe) Match(int) bool    { return true }
+func (passiveProbe) Probe(conn net.Conn, host string, timeout time.Duration) (string, map[string]string, error) {
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	if n == 0 {
+		return "", nil, err
+	}
+	return strings.TrimSpace(string(buf[:n])), nil, nil
+}
+
+// httpProbe sends a minimal HTTP/1.0 request and pulls the Server
+// header out of the response.
+type httpProbe struct{}
+
+func (httpProbe) Name() string   { return "http" }
+func (httpProbe) Match(port int) bool {
+	switch port {
+	case 80, 8080, 8000, 8888:
+		return true
+	default:
+		return false
+	}
+}
+
+func (httpProbe) Probe(conn net.Conn, host string, timeout time.Duration) (string, map[string]string, error) {
+	conn.SetDeadline(time.Now().Add(timeout))
+	req := fmt.Sprintf("GET / HTTP/1.0\r\nHost: %s\r\n\r\n", host)
+	if _, err := conn.Write([]byte(req)); err != nil {
+		return "", nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	statusLine, err := reader.ReadString('\n')
+	if err != nil {
+		return "", nil, err
+	}
+	banner := strings.TrimSpace(statusLine)
+
+	meta := map[string]string{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(strings.TrimSpace(name), "server") {
+			meta["server"] = strings.TrimSpace(value)
+		}
+	}
+
+	return banner, meta, nil
+}
+
+// tlsProbe performs a TLS handshake and records the negotiated version,
+// cipher suite, and the leaf certificate's CN/SANs.
+type tlsProbe struct{}
+
+func (tlsProbe) Name() string { return "tls" }
+func (tlsProbe) Match(port int) bool {
+	switch port {
+	case 443, 8443:
+		return true
+	default:
+		return false
+	}
+}
+
+func (tlsProbe) Probe(conn net.Conn, host string, timeout time.Duration) (string, map[string]string, error) {
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+	})
+	if err := tlsConn.Handshake(); err != nil {
+		return "", nil, err
+	}
+
+	state := tlsConn.ConnectionState()
+	meta := map[string]string{
+		"tls_version": tlsVersionName(state.Version),
+		"cipher":      tls.CipherSuiteName(state.CipherSuite),
+	}
+
+	banner := meta["tls_version"]
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		meta["common_name"] = cert.Subject.CommonName
+		if len(cert.DNSNames) > 0 {
+			meta["sans"] = strings.Join(cert.DNSNames, ",")
+		}
+		banner = fmt.Sprintf("%s (CN=%s)", meta["tls_version"], cert.Subject.CommonName)
+	}
+
+	return banner, meta, nil
+}
+
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS1.0"
+	case tls.VersionTLS11:
+		return "TLS1.1"
+	case tls.VersionTLS12:
+		return "TLS1.2"
+	case tls.VersionTLS13:
+		return "TLS1.3"
+	default:
+		return fmt.Sprintf("unknown(0x%04x)", version)
+	}
+}
+
+// sshProbe reads the SSH identification string, which servers send
+// unprompted as soon as the TCP connection is established.
+type sshProbe struct{}
+
+func (sshProbe) Name() string          { return "ssh" }
+func (sshProbe) Match(port int) bool   { return port == 22 }
+func (sshProbe) Probe(conn net.Conn, host string, timeout time.Duration) (string, map[string]string, error) {
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	banner := strings.TrimSpace(line)
+	if banner == "" {
+		return "", nil, err
+	}
+	return banner, nil, nil
+}
+
+// smtpProbe, ftpProbe and pop3Probe all just read the greeting line the
+// server sends as soon as the connection is accepted.
+type smtpProbe struct{}
+
+func (smtpProbe) Name() string        { return "smtp" }
+func (smtpProbe) Match(port int) bool { return port == 25 || port == 587 }
+func (smtpProbe) Probe(conn net.Conn, host string, timeout time.Duration) (string, map[string]string, error) {
+	return readGreeting(conn, timeout)
+}
+
+type ftpProbe struct{}
+
+func (ftpProbe) Name() string        { return "ftp" }
+func (ftpProbe) Match(port int) bool { return port == 21 }
+func (ftpProbe) Probe(conn net.Conn, host string, timeout time.Duration) (string, map[string]string, error) {
+	return readGreeting(conn, timeout)
+}
+
+type pop3Probe struct{}
+
+func (pop3Probe) Name() string        { return "pop3" }
+func (pop3Probe) Match(port int) bool { return port == 110 }
+func (pop3Probe) Probe(conn net.Conn, host string, timeout time.Duration) (string, map[string]string, error) {
+	return readGreeting(conn, timeout)
+}
+
+func readGreeting(conn net.Conn, timeout time.Duration) (string, map[string]string, error) {
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	banner := strings.TrimSpace(line)
+	if banner == "" {
+		return "", nil, err
+	}
+	return banner, nil, nil
+}
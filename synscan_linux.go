@@ -0,0 +1,273 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// synResponse is what the demux reader hands back to a waiting Scan
+// call: just the TCP flags byte of a segment matching its (ip, port).
+type synResponse struct {
+	flags byte
+}
+
+// waiterKey identifies an in-flight SYN probe by the peer IP and port
+// it's waiting on a reply from, since every concurrent scan shares one
+// source port (e.srcPort).
+type waiterKey struct {
+	ip   string
+	port int
+}
+
+// synEngine implements a half-open SYN scan using a raw IPv4/TCP socket:
+// it crafts and sends a bare SYN, then watches for a matching SYN-ACK or
+// RST, and never completes the handshake. Requires CAP_NET_RAW (root in
+// practice), checked by newSYNEngine.
+//
+// All workers share one raw receive socket, so a single readLoop
+// goroutine demultiplexes incoming segments to the right Scan call by
+// (source IP, source port) instead of letting every worker race its own
+// Recvfrom on the shared fd - with N readers, a reply for worker A's
+// port is frequently stolen by worker B's read and silently dropped.
+type synEngine struct {
+	timeout time.Duration
+	srcPort int
+
+	sendMu sync.Mutex
+	sendFD int
+
+	waitersMu sync.Mutex
+	waiters   map[waiterKey]chan synResponse
+}
+
+func newSYNEngine(timeout time.Duration) (ScanEngine, error) {
+	sendFD, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_RAW, syscall.IPPROTO_TCP)
+	if err != nil {
+		return nil, fmt.Errorf("opening raw send socket: %w", err)
+	}
+	if err := syscall.SetsockoptInt(sendFD, syscall.IPPROTO_IP, syscall.IP_HDRINCL, 1); err != nil {
+		syscall.Close(sendFD)
+		return nil, fmt.Errorf("setting IP_HDRINCL: %w", err)
+	}
+
+	recvFD, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_RAW, syscall.IPPROTO_TCP)
+	if err != nil {
+		syscall.Close(sendFD)
+		return nil, fmt.Errorf("opening raw receive socket: %w", err)
+	}
+
+	e := &synEngine{
+		timeout: timeout,
+		srcPort: 40000 + os.Getpid()%20000,
+		sendFD:  sendFD,
+		waiters: make(map[waiterKey]chan synResponse),
+	}
+	go e.readLoop(recvFD)
+	return e, nil
+}
+
+// readLoop is the sole reader of recvFD for the lifetime of the engine.
+// It parses each inbound segment and fans it out to whichever Scan call
+// registered a waiter for that (source IP, source port) pair.
+func (e *synEngine) readLoop(recvFD int) {
+	buf := make([]byte, 4096)
+	for {
+		n, from, err := syscall.Recvfrom(recvFD, buf, 0)
+		if err != nil {
+			continue
+		}
+		fromAddr, ok := from.(*syscall.SockaddrInet4)
+		if !ok {
+			continue
+		}
+
+		srcPort, flags, ok := parseTCPSegment(buf[:n], e.srcPort)
+		if !ok {
+			continue
+		}
+
+		key := waiterKey{ip: net.IP(fromAddr.Addr[:]).String(), port: srcPort}
+		e.waitersMu.Lock()
+		ch := e.waiters[key]
+		e.waitersMu.Unlock()
+		if ch == nil {
+			continue
+		}
+		select {
+		case ch <- synResponse{flags: flags}:
+		default:
+			// Waiter isn't ready for another reply (already has one
+			// buffered); drop rather than block the shared reader.
+		}
+	}
+}
+
+func (e *synEngine) Scan(ctx context.Context, ip, host string, port int) ScanResult {
+	dstIP, err := resolveIPv4(ip)
+	if err != nil {
+		return ScanResult{Target: ip, Port: port, Open: false}
+	}
+
+	srcIP, err := localIPv4For(dstIP)
+	if err != nil {
+		return ScanResult{Target: ip, Port: port, Open: false}
+	}
+
+	key := waiterKey{ip: dstIP.String(), port: port}
+	ch := make(chan synResponse, 4)
+	e.waitersMu.Lock()
+	e.waiters[key] = ch
+	e.waitersMu.Unlock()
+	defer func() {
+		e.waitersMu.Lock()
+		delete(e.waiters, key)
+		e.waitersMu.Unlock()
+	}()
+
+	seq := uint32(time.Now().UnixNano())
+	packet := buildSYNPacket(srcIP, dstIP, e.srcPort, port, seq)
+
+	var sockAddr syscall.SockaddrInet4
+	copy(sockAddr.Addr[:], dstIP.To4())
+
+	e.sendMu.Lock()
+	err = syscall.Sendto(e.sendFD, packet, 0, &sockAddr)
+	e.sendMu.Unlock()
+	if err != nil {
+		return ScanResult{Target: ip, Port: port, Open: false}
+	}
+
+	timer := time.NewTimer(e.timeout)
+	defer timer.Stop()
+
+	const synAck = 0x12 // SYN|ACK
+	const rst = 0x04
+	for {
+		select {
+		case resp := <-ch:
+			switch {
+			case resp.flags&synAck == synAck:
+				return ScanResult{Target: ip, Port: port, Open: true, Banner: "syn-ack"}
+			case resp.flags&rst != 0:
+				return ScanResult{Target: ip, Port: port, Open: false, Banner: "rst"}
+			}
+			// Anything else (e.g. a bare ACK) isn't conclusive; keep
+			// waiting for a SYN-ACK or RST until the timer fires.
+		case <-timer.C:
+			// No SYN-ACK or RST within the timeout: the port is filtered.
+			return ScanResult{Target: ip, Port: port, Open: false, Banner: "filtered"}
+		case <-ctx.Done():
+			return ScanResult{Target: ip, Port: port, Open: false, Banner: "cancelled"}
+		}
+	}
+}
+
+func resolveIPv4(target string) (net.IP, error) {
+	ips, err := net.LookupIP(target)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		if v4 := ip.To4(); v4 != nil {
+			return v4, nil
+		}
+	}
+	return nil, fmt.Errorf("no IPv4 address for %s", target)
+}
+
+// localIPv4For finds the local address the kernel would use to reach
+// dst, by opening (but not connecting, for UDP) a throwaway socket.
+func localIPv4For(dst net.IP) (net.IP, error) {
+	conn, err := net.Dial("udp4", net.JoinHostPort(dst.String(), "80"))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	local := conn.LocalAddr().(*net.UDPAddr)
+	return local.IP.To4(), nil
+}
+
+// buildSYNPacket assembles a bare IPv4+TCP SYN segment with IP_HDRINCL,
+// so the kernel sends it as-is instead of prepending its own IP header.
+func buildSYNPacket(src, dst net.IP, srcPort, dstPort int, seq uint32) []byte {
+	ipHeader := make([]byte, 20)
+	ipHeader[0] = 0x45 // version 4, IHL 5
+	ipHeader[1] = 0x00
+	binary.BigEndian.PutUint16(ipHeader[2:4], 40) // total length
+	binary.BigEndian.PutUint16(ipHeader[4:6], uint16(seq))
+	ipHeader[8] = 64                // TTL
+	ipHeader[9] = syscall.IPPROTO_TCP
+	copy(ipHeader[12:16], src.To4())
+	copy(ipHeader[16:20], dst.To4())
+	binary.BigEndian.PutUint16(ipHeader[10:12], ipChecksum(ipHeader))
+
+	tcpHeader := make([]byte, 20)
+	binary.BigEndian.PutUint16(tcpHeader[0:2], uint16(srcPort))
+	binary.BigEndian.PutUint16(tcpHeader[2:4], uint16(dstPort))
+	binary.BigEndian.PutUint32(tcpHeader[4:8], seq)
+	binary.BigEndian.PutUint32(tcpHeader[8:12], 0) // ack
+	tcpHeader[12] = 0x50                           // data offset 5
+	tcpHeader[13] = 0x02                           // SYN
+	binary.BigEndian.PutUint16(tcpHeader[14:16], 65535)
+
+	checksum := tcpChecksum(src, dst, tcpHeader)
+	binary.BigEndian.PutUint16(tcpHeader[16:18], checksum)
+
+	return append(ipHeader, tcpHeader...)
+}
+
+// parseTCPSegment pulls the source port and flags out of a raw IPv4+TCP
+// datagram the kernel handed back to us, filtering for the destination
+// port we sent from (localPort, shared by every in-flight scan).
+func parseTCPSegment(pkt []byte, localPort int) (srcPort int, flags byte, ok bool) {
+	if len(pkt) < 20 {
+		return 0, 0, false
+	}
+	ihl := int(pkt[0]&0x0f) * 4
+	if len(pkt) < ihl+20 {
+		return 0, 0, false
+	}
+	tcp := pkt[ihl:]
+	srcPort = int(binary.BigEndian.Uint16(tcp[0:2]))
+	dstPort := int(binary.BigEndian.Uint16(tcp[2:4]))
+	if dstPort != localPort {
+		return 0, 0, false
+	}
+	return srcPort, tcp[13], true
+}
+
+func ipChecksum(header []byte) uint16 {
+	return checksum(header)
+}
+
+func tcpChecksum(src, dst net.IP, tcpHeader []byte) uint16 {
+	pseudo := make([]byte, 12+len(tcpHeader))
+	copy(pseudo[0:4], src.To4())
+	copy(pseudo[4:8], dst.To4())
+	pseudo[9] = syscall.IPPROTO_TCP
+	binary.BigEndian.PutUint16(pseudo[10:12], uint16(len(tcpHeader)))
+	copy(pseudo[12:], tcpHeader)
+	return checksum(pseudo)
+}
+
+func checksum(data []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(data[i : i+2]))
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = (sum & 0xffff) + (sum >> 16)
+	}
+	return ^uint16(sum)
+}
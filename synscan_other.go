@@ -0,0 +1,15 @@
+//go:build !linux
+
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// newSYNEngine is only implemented on Linux, where raw IPv4/TCP sockets
+// are available; validateConfig already requires root for --scan-type=syn,
+// but the raw-socket plumbing itself is platform-specific.
+func newSYNEngine(timeout time.Duration) (ScanEngine, error) {
+	return nil, fmt.Errorf("--scan-type=syn is only supported on Linux")
+}
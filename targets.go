@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// expandConfigTargets replaces config.Targets with the fully expanded,
+// deduplicated target list: CIDR blocks and IP ranges are enumerated,
+// @file.txt entries are read in, and plain hostnames/IPs pass through
+// unchanged.
+func expandConfigTargets(config *Config) error {
+	expanded, err := expandTargets(config.Targets)
+	if err != nil {
+		return err
+	}
+	config.Targets = expanded
+	return nil
+}
+
+// expandTargets turns a raw target list (as given to --target/--targets)
+// into a flat, deduplicated list of literal targets, expanding any CIDR
+// blocks (10.0.0.0/24), IP ranges (10.0.0.1-10.0.0.50), and @file.txt
+// include directives along the way.
+func expandTargets(raw []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var out []string
+
+	add := func(t string) {
+		t = strings.TrimSpace(t)
+		if t == "" || seen[t] {
+			return
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+
+	var expand func(entries []string) error
+	expand = func(entries []string) error {
+		for _, entry := range entries {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+
+			switch {
+			case strings.HasPrefix(entry, "@"):
+				lines, err := readTargetFile(strings.TrimPrefix(entry, "@"))
+				if err != nil {
+					return fmt.Errorf("reading target file %s: %w", entry, err)
+				}
+				if err := expand(lines); err != nil {
+					return err
+				}
+
+			case strings.Contains(entry, "/"):
+				ips, err := expandCIDR(entry)
+				if err != nil {
+					return fmt.Errorf("parsing CIDR %s: %w", entry, err)
+				}
+				for _, ip := range ips {
+					add(ip)
+				}
+
+			case strings.Contains(entry, "-"):
+				ips, err := expandIPRange(entry)
+				if err != nil {
+					return fmt.Errorf("parsing range %s: %w", entry, err)
+				}
+				for _, ip := range ips {
+					add(ip)
+				}
+
+			default:
+				add(entry)
+			}
+		}
+		return nil
+	}
+
+	if err := expand(raw); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// readTargetFile reads one target per line, skipping blanks and #
+// comments, for @file.txt include directives.
+func readTargetFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// maxCIDRHosts guards against enumerating a CIDR block too large to hold
+// in memory, same cap as expandIPRange's maxRangeSize - a typo'd /8 (or
+// any IPv6 block, which is never smaller than a /64) would otherwise try
+// to build a multi-million/-billion entry slice with zero feedback.
+const maxCIDRHosts = 1 << 20
+
+// expandCIDR lists every host address in a CIDR block, skipping the
+// IPv4 network and broadcast addresses for blocks larger than a /31.
+// IPv6 has no broadcast address, and the all-zeros/all-ones addresses
+// are valid assignable unicast hosts, so they're kept for IPv6.
+func expandCIDR(cidr string) ([]string, error) {
+	base, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	if hostBits := bits - ones; hostBits > 20 {
+		return nil, fmt.Errorf("CIDR %q is too large (more than %d hosts)", cidr, maxCIDRHosts)
+	}
+
+	var ips []string
+	for cur := base.Mask(ipNet.Mask); ipNet.Contains(cur); incIP(cur) {
+		ips = append(ips, cur.String())
+	}
+
+	if base.To4() != nil && bits-ones > 1 && len(ips) > 2 {
+		ips = ips[1 : len(ips)-1]
+	}
+	return ips, nil
+}
+
+// expandIPRange expands an inclusive "10.0.0.1-10.0.0.50" style range.
+func expandIPRange(spec string) ([]string, error) {
+	parts := strings.SplitN(spec, "-", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid range %q", spec)
+	}
+
+	start := net.ParseIP(strings.TrimSpace(parts[0])).To4()
+	end := net.ParseIP(strings.TrimSpace(parts[1])).To4()
+	if start == nil || end == nil {
+		return nil, fmt.Errorf("invalid range %q", spec)
+	}
+
+	const maxRangeSize = 1 << 20 // guard against e.g. a typo'd /0-/0 range
+
+	var ips []string
+	for cur := append(net.IP{}, start...); ; incIP(cur) {
+		ips = append(ips, cur.String())
+		if cur.Equal(end) {
+			break
+		}
+		if len(ips) > maxRangeSize {
+			return nil, fmt.Errorf("range %q is too large", spec)
+		}
+	}
+	return ips, nil
+}
+
+// incIP increments an IP address in place, handling the carry across
+// octets (or 16-bit groups for IPv6).
+func incIP(ip net.IP) {
+	for i := len(ip) - 1; i >= 0; i-- {
+		ip[i]++
+		if ip[i] != 0 {
+			break
+		}
+	}
+}
+
+// resolveTargets resolves each hostname in targets to its first A/AAAA
+// record once up front, so every worker dials the same cached IP
+// instead of re-resolving per connection. Targets that are already
+// literal IPs, or that fail to resolve, map to themselves.
+func resolveTargets(targets []string) map[string]string {
+	resolved := make(map[string]string, len(targets))
+	for _, target := range targets {
+		if net.ParseIP(target) != nil {
+			resolved[target] = target
+			continue
+		}
+
+		ips, err := net.LookupHost(target)
+		if err != nil || len(ips) == 0 {
+			fmt.Fprintf(os.Stderr, "warning: could not resolve %s, using as-is: %v\n", target, err)
+			resolved[target] = target
+			continue
+		}
+		resolved[target] = ips[0]
+	}
+	return resolved
+}